@@ -0,0 +1,39 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha4
+
+// defaultKrustletRuntime is the WASM provider a krustlet-worker Node
+// installs when KrustletRuntime.Runtime is unset.
+const defaultKrustletRuntime = "wasi"
+
+// SetDefaultsCluster defaults every Node in cfg.
+func SetDefaultsCluster(cfg *Cluster) {
+	for i := range cfg.Nodes {
+		SetDefaultsNode(&cfg.Nodes[i])
+	}
+}
+
+// SetDefaultsNode defaults node, including its KrustletRuntime selection
+// if set.
+func SetDefaultsNode(node *Node) {
+	if node.Role == "" {
+		node.Role = WorkerRole
+	}
+	if node.KrustletRuntime != nil && node.KrustletRuntime.Runtime == "" {
+		node.KrustletRuntime.Runtime = defaultKrustletRuntime
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha4 implements the v1alpha4 apiVersion of kind's
+// configuration file format
+package v1alpha4
+
+import "sigs.k8s.io/kind/pkg/cluster/constants"
+
+// NodeRole defines possible role for nodes in a Kubernetes cluster managed
+// by `kind`
+type NodeRole string
+
+const (
+	// ControlPlaneRole identifies a node that hosts the Kubernetes control
+	// plane
+	ControlPlaneRole NodeRole = "control-plane"
+	// WorkerRole identifies a node that only joins the cluster as a worker
+	WorkerRole NodeRole = "worker"
+	// KrustletWorkerRole identifies a node that joins the cluster as a
+	// krustlet-backed worker, so joining actions can select it (and its
+	// KrustletRuntime) instead of the regular kubelet-backed WorkerRole.
+	KrustletWorkerRole NodeRole = NodeRole(constants.KrustletNodeRoleValue)
+)
+
+// Cluster contains kind cluster configuration
+type Cluster struct {
+	// Name is the cluster context name
+	Name string
+	// Nodes contains the list of nodes defined in the `kind` Cluster
+	Nodes []Node
+}
+
+// Node contains configuration for a `kind` Node, and options for
+// customizing the newly created Node
+type Node struct {
+	// Role defines the role of the node in the in the Kubernetes cluster
+	// managed by `kind`
+	Role NodeRole
+	// Image is the node image to use when creating this Node
+	Image string
+	// Labels are Kubernetes labels applied to the Node once it joins the
+	// cluster
+	Labels map[string]string
+
+	// KrustletRuntime pins the WASM provider a krustlet-worker Node
+	// installs, along with the version and checksum to install. It is
+	// only consulted for nodes whose Role selects the krustlet worker
+	// role; unset, the node installs the built-in default runtime at the
+	// built-in pinned version.
+	KrustletRuntime *KrustletRuntime
+}
+
+// KrustletRuntime selects the WASM runtime (and pinned release) a
+// krustlet-worker Node installs, so a single cluster can host several
+// runtimes side by side.
+type KrustletRuntime struct {
+	// Runtime selects the WASM provider: "wasi", "wascc" or "wasmcloud".
+	// Defaults to "wasi".
+	Runtime string
+	// Version is the krustlet release tag to install, e.g.
+	// "v1.0.0-alpha.1". Defaults to the built-in pinned version for
+	// Runtime.
+	Version string
+	// Checksum is the sha256 checksum (in "sha256:<hex>" form) the
+	// downloaded binary must match. There is no built-in default: when
+	// unset, the downloaded binary is not checksum-verified.
+	Checksum string
+}
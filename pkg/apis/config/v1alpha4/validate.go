@@ -0,0 +1,60 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha4
+
+import "sigs.k8s.io/kind/pkg/errors"
+
+// validKrustletRuntimes enumerates the WASM providers krustletjoin knows
+// how to install.
+var validKrustletRuntimes = map[string]bool{
+	"wasi":      true,
+	"wascc":     true,
+	"wasmcloud": true,
+}
+
+// validNodeRoles enumerates the Role values a Node may select.
+var validNodeRoles = map[NodeRole]bool{
+	ControlPlaneRole:   true,
+	WorkerRole:         true,
+	KrustletWorkerRole: true,
+}
+
+// ValidateCluster validates cfg, including every Node's KrustletRuntime
+// selection.
+func ValidateCluster(cfg *Cluster) error {
+	for i := range cfg.Nodes {
+		if err := ValidateNode(&cfg.Nodes[i]); err != nil {
+			return errors.Wrapf(err, "node %d", i)
+		}
+	}
+	return nil
+}
+
+// ValidateNode validates node's configuration, including its
+// KrustletRuntime selection if set.
+func ValidateNode(node *Node) error {
+	if node.Role != "" && !validNodeRoles[node.Role] {
+		return errors.Errorf("role must be one of %q, %q, %q, got %q", ControlPlaneRole, WorkerRole, KrustletWorkerRole, node.Role)
+	}
+	if node.KrustletRuntime == nil || node.KrustletRuntime.Runtime == "" {
+		return nil
+	}
+	if !validKrustletRuntimes[node.KrustletRuntime.Runtime] {
+		return errors.Errorf("KrustletRuntime.Runtime must be one of wasi, wascc, wasmcloud, got %q", node.KrustletRuntime.Runtime)
+	}
+	return nil
+}
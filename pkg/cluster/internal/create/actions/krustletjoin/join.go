@@ -18,28 +18,73 @@ limitations under the License.
 package krustletjoin
 
 import (
-	"strings"
 	"time"
 
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
 	"sigs.k8s.io/kind/pkg/cluster/constants"
 	"sigs.k8s.io/kind/pkg/cluster/nodes"
 	"sigs.k8s.io/kind/pkg/errors"
-	"sigs.k8s.io/kind/pkg/log"
 
 	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
 
 	"sigs.k8s.io/kind/pkg/cluster/internal/create/actions"
-	"sigs.k8s.io/kind/pkg/cluster/internal/providers"
+	"sigs.k8s.io/kind/pkg/cluster/internal/create/actions/krustletjoin/lifecycle"
 	"sigs.k8s.io/kind/pkg/exec"
 )
 
+// defaultCSRTimeout is how long a worker waits, in total, for its
+// kubelet-serving CSR to appear before giving up.
+const defaultCSRTimeout = 30 * time.Second
+
 // Action implements action for creating the kubeadm join
 // and deploying it on the bootstrap control-plane node.
-type Action struct{}
+type Action struct {
+	csrTimeout     time.Duration
+	runner         exec.CommandRunner
+	extraLifecycle []lifecycle.Action
+}
+
+// Option is used to configure NewAction.
+type Option func(*Action)
+
+// WithCSRTimeout sets how long the action waits for a worker's kubelet
+// CSR to appear before giving up. CI environments with slow cold starts
+// may need to raise this above the default.
+func WithCSRTimeout(timeout time.Duration) Option {
+	return func(a *Action) {
+		a.csrTimeout = timeout
+	}
+}
+
+// WithCommandRunner overrides the exec.CommandRunner the action uses to run
+// commands against nodes. It exists so tests can inject a fake instead of
+// shelling out to a real container.
+func WithCommandRunner(runner exec.CommandRunner) Option {
+	return func(a *Action) {
+		a.runner = runner
+	}
+}
+
+// WithExtraLifecycleActions appends additional lifecycle.Actions to the
+// end of the built-in join sequence, so third parties can hook in extra
+// join-time steps (loading local OCI WASM modules, applying taints,
+// registering with a private registry, ...) without editing this package.
+func WithExtraLifecycleActions(extra ...lifecycle.Action) Option {
+	return func(a *Action) {
+		a.extraLifecycle = append(a.extraLifecycle, extra...)
+	}
+}
 
 // NewAction returns a new action for creating the kubeadm jion
-func NewAction() actions.Action {
-	return &Action{}
+func NewAction(opts ...Option) actions.Action {
+	a := &Action{
+		csrTimeout: defaultCSRTimeout,
+		runner:     exec.DefaultCommandRunner{},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
 // Execute runs the action
@@ -60,7 +105,7 @@ func (a *Action) Execute(ctx *actions.ActionContext) error {
 		return err
 	}
 	if len(workers) > 0 {
-		if err := joinWorkers(ctx, workers, cpNodes[0]); err != nil {
+		if err := a.joinWorkers(ctx, workers, cpNodes[0]); err != nil {
 			return err
 		}
 	}
@@ -68,7 +113,7 @@ func (a *Action) Execute(ctx *actions.ActionContext) error {
 	return nil
 }
 
-func joinWorkers(
+func (a *Action) joinWorkers(
 	ctx *actions.ActionContext,
 	workers []nodes.Node,
 	cpNode nodes.Node,
@@ -76,12 +121,32 @@ func joinWorkers(
 	ctx.Status.Start("Joining krustlet nodes 🦀")
 	defer ctx.Status.End(false)
 
+	// the kind config lists krustlet nodes in the same order the provider
+	// created them in, so we can zip the two slices together to find the
+	// pinned runtime for each worker
+	krustletNodeConfigs := selectNodesByRole(ctx.Config.Nodes, constants.KrustletNodeRoleValue)
+
+	engine := lifecycle.NewEngine(append(lifecycle.DefaultActions(), a.extraLifecycle...)...)
+
 	// create the workers concurrently
 	fns := []func() error{}
-	for _, node := range workers {
+	for i, node := range workers {
 		node := node // capture loop variable
+		var nodeConfig *v1alpha4.Node
+		if i < len(krustletNodeConfigs) {
+			nodeConfig = krustletNodeConfigs[i]
+		}
+		spec := lifecycle.RuntimeSpecForNode(nodeConfig)
 		fns = append(fns, func() error {
-			return runKrustletJoin(ctx.Logger, node, ctx.Provider, ctx.Config.Name, cpNode)
+			lifecycleCtx := &lifecycle.Context{
+				Logger:     ctx.Logger,
+				Node:       node,
+				CPNode:     cpNode,
+				Runner:     a.runner,
+				Runtime:    spec,
+				CSRTimeout: a.csrTimeout,
+			}
+			return engine.Run(lifecycleCtx)
 		})
 	}
 	if err := errors.UntilErrorConcurrent(fns); err != nil {
@@ -92,79 +157,14 @@ func joinWorkers(
 	return nil
 }
 
-// runKrustletJoin starts the krustlet and approves the csr
-func runKrustletJoin(logger log.Logger, node nodes.Node, provider providers.Provider, name string, cpNode nodes.Node) error {
-
-	cmd := cpNode.Command(
-		"curl", "-sSL", "https://raw.githubusercontent.com/krustlet/krustlet/main/scripts/bootstrap.sh",
-	)
-	script, err := exec.CombinedOutputLines(cmd)
-	logger.V(3).Info(strings.Join(script, "\n"))
-	if err != nil {
-		return errors.Wrap(err, "failed to download krustlet bootstrap token script")
-	}
-
-	cmd = cpNode.Command(
-		"bash", "-c", strings.Join(script, "\n"),
-	)
-	lines, err := exec.CombinedOutputLines(cmd)
-	logger.V(3).Info(strings.Join(lines, "\n"))
-	if err != nil {
-		return errors.Wrap(err, "failed to execute krustlet bootstrap token script")
-	}
-
-	cmd = cpNode.Command(
-		"cat", "/root/.krustlet/config/bootstrap.conf",
-	)
-	bootconf, err := exec.CombinedOutputLines(cmd)
-	logger.V(3).Info(strings.Join(bootconf, "\n"))
-	if err != nil {
-		return errors.Wrap(err, "failed to fetch bootstrap.conf")
-	}
-
-	err = nodeutils.WriteFile(node, "/etc/kubernetes/bootstrap-kubelet.conf", strings.Join(bootconf, "\n"))
-	if err != nil {
-		return errors.Wrap(err, "failed to write kubeconfig")
-	}
-
-	cmd = node.Command(
-		"systemctl", "enable", "krustlet",
-	)
-	lines, err = exec.CombinedOutputLines(cmd)
-	logger.V(3).Info(strings.Join(lines, "\n"))
-	if err != nil {
-		return errors.Wrap(err, "failed to enable krustlet sevice")
-	}
-
-	cmd = node.Command(
-		"systemctl", "start", "krustlet",
-	)
-	lines, err = exec.CombinedOutputLines(cmd)
-	logger.V(3).Info(strings.Join(lines, "\n"))
-	if err != nil {
-		return errors.Wrap(err, "failed to run `systemctl start krustlet`")
-	}
-
-	for i := 0; i <= 30; i++ {
-		err = cpNode.Command(
-			"kubectl", "--kubeconfig", "/etc/kubernetes/admin.conf", "get", "csr", node.String()+"-tls",
-		).Run()
-		if err == nil {
-			break
-		} else {
-			logger.V(2).Info(err.Error())
+// selectNodesByRole returns the kind config Node entries matching role, in
+// the order they appear in the config.
+func selectNodesByRole(configNodes []v1alpha4.Node, role string) []*v1alpha4.Node {
+	selected := []*v1alpha4.Node{}
+	for i := range configNodes {
+		if string(configNodes[i].Role) == role {
+			selected = append(selected, &configNodes[i])
 		}
-		time.Sleep(time.Second)
 	}
-
-	cmd = cpNode.Command(
-		"kubectl", "--kubeconfig", "/etc/kubernetes/admin.conf", "certificate", "approve", node.String()+"-tls",
-	)
-	lines, err = exec.CombinedOutputLines(cmd)
-	logger.V(3).Info(strings.Join(lines, "\n"))
-	if err != nil {
-		return errors.Wrap(err, "failed to run `systemctl start krustlet`")
-	}
-
-	return nil
+	return selected
 }
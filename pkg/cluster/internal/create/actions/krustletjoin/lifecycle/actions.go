@@ -0,0 +1,357 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/util"
+)
+
+// writeFile writes content to dest on ctx.Node through ctx.Runner, instead
+// of nodeutils.WriteFile, which calls node.Command directly and would
+// bypass a fake Runner in tests.
+func writeFile(ctx *Context, dest, content string) error {
+	if _, err := ctx.Runner.RunCmd(ctx.Node.Command("mkdir", "-p", filepath.Dir(dest))); err != nil {
+		return errors.Wrap(err, "failed to create parent directory")
+	}
+	_, err := ctx.Runner.RunWithStdin(ctx.Node.Command("cp", "/dev/stdin", dest), strings.NewReader(content))
+	return err
+}
+
+// FetchBootstrapConf runs the embedded bootstrap script on the
+// control-plane node and reads back the bootstrap.conf it produces.
+type FetchBootstrapConf struct{}
+
+// Name implements Action
+func (a *FetchBootstrapConf) Name() string { return "FetchBootstrapConf" }
+
+// Precondition implements Action
+func (a *FetchBootstrapConf) Precondition(ctx *Context) error {
+	if ctx.CPNode == nil {
+		return errors.New("no control-plane node to fetch bootstrap.conf from")
+	}
+	return nil
+}
+
+// Do implements Action
+func (a *FetchBootstrapConf) Do(ctx *Context) error {
+	cmd := ctx.CPNode.Command("bash")
+	lines, err := ctx.Runner.RunWithStdin(cmd, strings.NewReader(bootstrapScript))
+	ctx.Logger.V(3).Info(strings.Join(lines, "\n"))
+	if err != nil {
+		return errors.Wrap(err, "failed to execute krustlet bootstrap token script")
+	}
+
+	cmd = ctx.CPNode.Command("cat", "/root/.krustlet/config/bootstrap.conf")
+	bootconf, err := ctx.Runner.RunCmd(cmd)
+	ctx.Logger.V(3).Info(strings.Join(bootconf, "\n"))
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch bootstrap.conf")
+	}
+
+	ctx.Bootconf = strings.Join(bootconf, "\n")
+	return nil
+}
+
+// Undo implements Action. The bootstrap.conf lives on the control-plane
+// node and carries no state worth tearing down on its own.
+func (a *FetchBootstrapConf) Undo(ctx *Context) error { return nil }
+
+// WriteKubeconfig writes the fetched bootstrap.conf onto the worker node
+// as its kubelet bootstrap kubeconfig.
+type WriteKubeconfig struct{}
+
+// Name implements Action
+func (a *WriteKubeconfig) Name() string { return "WriteKubeconfig" }
+
+// Precondition implements Action
+func (a *WriteKubeconfig) Precondition(ctx *Context) error {
+	if ctx.Bootconf == "" {
+		return errors.New("no bootstrap.conf to write")
+	}
+	return nil
+}
+
+// Do implements Action
+func (a *WriteKubeconfig) Do(ctx *Context) error {
+	return errors.Wrap(
+		writeFile(ctx, "/etc/kubernetes/bootstrap-kubelet.conf", ctx.Bootconf),
+		"failed to write kubeconfig",
+	)
+}
+
+// Undo implements Action
+func (a *WriteKubeconfig) Undo(ctx *Context) error {
+	_, err := ctx.Runner.RunCmd(ctx.Node.Command("rm", "-f", "/etc/kubernetes/bootstrap-kubelet.conf"))
+	return err
+}
+
+// InstallRuntime downloads the pinned runtime binary onto the worker
+// node, verifies it against the pinned checksum if one is set, and
+// templates the systemd unit that will run it.
+type InstallRuntime struct{}
+
+// Name implements Action
+func (a *InstallRuntime) Name() string { return "InstallRuntime" }
+
+// Precondition implements Action
+func (a *InstallRuntime) Precondition(ctx *Context) error {
+	if ctx.Runtime.Version == "" {
+		return errors.New("no krustlet runtime pinned for this node")
+	}
+	return nil
+}
+
+// Do implements Action
+func (a *InstallRuntime) Do(ctx *Context) error {
+	spec := ctx.Runtime
+	binaryURL := fmt.Sprintf(
+		"https://github.com/krustlet/krustlet/releases/download/%s/%s",
+		spec.Version, spec.BinaryName(),
+	)
+
+	cmd := ctx.Node.Command("curl", "-sSL", "-o", "/usr/local/bin/"+spec.UnitName(), binaryURL)
+	lines, err := ctx.Runner.RunCmd(cmd)
+	ctx.Logger.V(3).Info(strings.Join(lines, "\n"))
+	if err != nil {
+		return errors.Wrap(err, "failed to download krustlet "+string(spec.Runtime)+" binary")
+	}
+
+	// Only verify the download against a checksum if one was pinned: kind
+	// does not ship a built-in checksum for every krustlet release, and a
+	// fabricated one would fail verification on every real download.
+	if spec.Checksum != "" {
+		cmd = ctx.Node.Command(
+			"bash", "-c",
+			fmt.Sprintf("echo '%s  /usr/local/bin/%s' | sha256sum -c -", strings.TrimPrefix(spec.Checksum, "sha256:"), spec.UnitName()),
+		)
+		lines, err = ctx.Runner.RunCmd(cmd)
+		ctx.Logger.V(3).Info(strings.Join(lines, "\n"))
+		if err != nil {
+			return errors.Wrap(err, "krustlet "+string(spec.Runtime)+" binary failed checksum verification")
+		}
+	}
+
+	if _, err := ctx.Runner.RunCmd(ctx.Node.Command("chmod", "+x", "/usr/local/bin/"+spec.UnitName())); err != nil {
+		return errors.Wrap(err, "failed to mark krustlet binary executable")
+	}
+
+	return errors.Wrap(writeUnitFile(ctx), "failed to write krustlet systemd unit")
+}
+
+// writeUnitFile (re)writes the runtime's systemd unit, including the
+// registry endpoint TrustRegistry has provisioned trust for, if any.
+func writeUnitFile(ctx *Context) error {
+	spec := ctx.Runtime
+	unitContents := fmt.Sprintf(`[Unit]
+Description=Krustlet (%[1]s)
+After=network.target
+
+[Service]
+Environment=KRUSTLET_NODE_NAME=%%H
+Environment=KRUSTLET_BOOTSTRAP_FILE=/etc/kubernetes/bootstrap-kubelet.conf
+Environment=KRUSTLET_OCI_REGISTRY=%[3]s
+ExecStart=/usr/local/bin/%[2]s
+Restart=always
+
+[Install]
+WantedBy=multi-user.target
+`, spec.Runtime, spec.UnitName(), ctx.RegistryEndpoint)
+
+	return writeFile(ctx, "/etc/systemd/system/"+spec.UnitName()+".service", unitContents)
+}
+
+// Undo implements Action
+func (a *InstallRuntime) Undo(ctx *Context) error {
+	spec := ctx.Runtime
+	_, err := ctx.Runner.RunCmd(ctx.Node.Command(
+		"rm", "-f",
+		"/usr/local/bin/"+spec.UnitName(),
+		"/etc/systemd/system/"+spec.UnitName()+".service",
+	))
+	return err
+}
+
+// TrustRegistry configures the worker node to trust the per-cluster OCI
+// registry `kind load wasm-module` pushes into, and plumbs its endpoint
+// into the runtime's systemd unit so krustlet can resolve modules from it
+// without hitting the internet. It is a no-op when ctx.RegistryEndpoint
+// is unset, which is the common case at cluster creation time (a wasm
+// registry is only provisioned once a user actually loads a module).
+type TrustRegistry struct{}
+
+// Name implements Action
+func (a *TrustRegistry) Name() string { return "TrustRegistry" }
+
+// Precondition implements Action
+func (a *TrustRegistry) Precondition(ctx *Context) error { return nil }
+
+// Do implements Action
+func (a *TrustRegistry) Do(ctx *Context) error {
+	if ctx.RegistryEndpoint == "" {
+		return nil
+	}
+
+	registriesConf := fmt.Sprintf(`unqualified-search-registries = ["%[1]s"]
+
+[[registry]]
+location = "%[1]s"
+insecure = true
+`, ctx.RegistryEndpoint)
+
+	if err := writeFile(ctx, "/etc/containers/registries.conf", registriesConf); err != nil {
+		return errors.Wrap(err, "failed to write registries.conf")
+	}
+
+	return errors.Wrap(writeUnitFile(ctx), "failed to plumb registry endpoint into krustlet unit")
+}
+
+// Undo implements Action
+func (a *TrustRegistry) Undo(ctx *Context) error {
+	if ctx.RegistryEndpoint == "" {
+		return nil
+	}
+	_, err := ctx.Runner.RunCmd(ctx.Node.Command("rm", "-f", "/etc/containers/registries.conf"))
+	return err
+}
+
+// EnableService enables the runtime's systemd unit on the worker node.
+type EnableService struct{}
+
+// Name implements Action
+func (a *EnableService) Name() string { return "EnableService" }
+
+// Precondition implements Action
+func (a *EnableService) Precondition(ctx *Context) error { return nil }
+
+// Do implements Action
+func (a *EnableService) Do(ctx *Context) error {
+	cmd := ctx.Node.Command("systemctl", "enable", ctx.Runtime.UnitName())
+	lines, err := ctx.Runner.RunCmd(cmd)
+	ctx.Logger.V(3).Info(strings.Join(lines, "\n"))
+	return errors.Wrap(err, "failed to enable krustlet service")
+}
+
+// Undo implements Action
+func (a *EnableService) Undo(ctx *Context) error {
+	_, err := ctx.Runner.RunCmd(ctx.Node.Command("systemctl", "disable", ctx.Runtime.UnitName()))
+	return err
+}
+
+// StartService starts the runtime's systemd unit on the worker node.
+type StartService struct{}
+
+// Name implements Action
+func (a *StartService) Name() string { return "StartService" }
+
+// Precondition implements Action
+func (a *StartService) Precondition(ctx *Context) error { return nil }
+
+// Do implements Action
+func (a *StartService) Do(ctx *Context) error {
+	cmd := ctx.Node.Command("systemctl", "start", ctx.Runtime.UnitName())
+	lines, err := ctx.Runner.RunCmd(cmd)
+	ctx.Logger.V(3).Info(strings.Join(lines, "\n"))
+	return errors.Wrap(err, "failed to run `systemctl start "+ctx.Runtime.UnitName()+"`")
+}
+
+// Undo implements Action
+func (a *StartService) Undo(ctx *Context) error {
+	_, err := ctx.Runner.RunCmd(ctx.Node.Command("systemctl", "stop", ctx.Runtime.UnitName()))
+	return err
+}
+
+// ApproveCSR waits for the worker's kubelet-serving CSR to appear and
+// approves it.
+type ApproveCSR struct{}
+
+// Name implements Action
+func (a *ApproveCSR) Name() string { return "ApproveCSR" }
+
+// Precondition implements Action
+func (a *ApproveCSR) Precondition(ctx *Context) error {
+	if ctx.CSRTimeout <= 0 {
+		return errors.New("no csr timeout configured")
+	}
+	return nil
+}
+
+// Do implements Action
+func (a *ApproveCSR) Do(ctx *Context) error {
+	const csrPollInterval = time.Second
+
+	err := util.Poll(ctx.CSRTimeout, csrPollInterval, func() error {
+		cmd := ctx.CPNode.Command(
+			"kubectl", "--kubeconfig", "/etc/kubernetes/admin.conf", "get", "csr", ctx.Node.String()+"-tls",
+		)
+		lines, err := ctx.Runner.RunCmd(cmd)
+		if err != nil {
+			ctx.Logger.V(2).Info(strings.Join(lines, "\n"))
+			return errors.Wrap(err, strings.Join(lines, "\n"))
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "timed out waiting for krustlet csr to appear")
+	}
+
+	cmd := ctx.CPNode.Command(
+		"kubectl", "--kubeconfig", "/etc/kubernetes/admin.conf", "certificate", "approve", ctx.Node.String()+"-tls",
+	)
+	lines, err := ctx.Runner.RunCmd(cmd)
+	ctx.Logger.V(3).Info(strings.Join(lines, "\n"))
+	return errors.Wrap(err, "failed to approve krustlet csr")
+}
+
+// Undo implements Action. A certificate approval cannot be meaningfully
+// reversed once kube-controller-manager has issued the certificate.
+func (a *ApproveCSR) Undo(ctx *Context) error { return nil }
+
+// LabelNode applies the runtime's node labels so modules can be scheduled
+// onto the right runtime deterministically.
+type LabelNode struct{}
+
+// Name implements Action
+func (a *LabelNode) Name() string { return "LabelNode" }
+
+// Precondition implements Action
+func (a *LabelNode) Precondition(ctx *Context) error { return nil }
+
+// Do implements Action
+func (a *LabelNode) Do(ctx *Context) error {
+	args := []string{"--kubeconfig", "/etc/kubernetes/admin.conf", "label", "node", ctx.Node.String(), "--overwrite"}
+	for k, v := range ctx.Runtime.NodeLabels() {
+		args = append(args, fmt.Sprintf("%s=%s", k, v))
+	}
+	_, err := ctx.Runner.RunCmd(ctx.CPNode.Command("kubectl", args...))
+	return errors.Wrap(err, "failed to label krustlet node")
+}
+
+// Undo implements Action
+func (a *LabelNode) Undo(ctx *Context) error {
+	args := []string{"--kubeconfig", "/etc/kubernetes/admin.conf", "label", "node", ctx.Node.String()}
+	for k := range ctx.Runtime.NodeLabels() {
+		args = append(args, k+"-")
+	}
+	_, err := ctx.Runner.RunCmd(ctx.CPNode.Command("kubectl", args...))
+	return err
+}
@@ -0,0 +1,27 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import _ "embed"
+
+// bootstrapScript generates a krustlet node's bootstrap.conf on the
+// control-plane node. It is bundled into the kind binary so joining a
+// krustlet node never has to fetch it from the internet at cluster
+// creation time.
+//
+//go:embed bootstrap.sh
+var bootstrapScript string
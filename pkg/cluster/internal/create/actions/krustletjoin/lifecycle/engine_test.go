@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/exec"
+	execfake "sigs.k8s.io/kind/pkg/exec/fake"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+// fakeNode implements the subset of nodes.Node the lifecycle actions call
+// directly (Command and String), so Engine.Run can be exercised without a
+// real container.
+type fakeNode struct{ name string }
+
+func (n *fakeNode) String() string { return n.name }
+
+func (n *fakeNode) Command(command string, args ...string) exec.Cmd {
+	return exec.Command(command, args...)
+}
+
+// TestEngineRunDefaultActions runs the built-in join sequence against a
+// fake CommandRunner and fake nodes, so regressions in the Do/Precondition
+// wiring show up without a real container.
+func TestEngineRunDefaultActions(t *testing.T) {
+	var callIndex int
+	runner := &execfake.CommandRunner{
+		Outputs: map[string][]string{
+			// FetchBootstrapConf's second call, `cat bootstrap.conf`.
+			"1": {"fake-bootstrap-conf-contents"},
+		},
+		Key: func(cmd exec.Cmd) string {
+			k := fmt.Sprintf("%d", callIndex)
+			callIndex++
+			return k
+		},
+	}
+
+	ctx := &Context{
+		Logger:     log.NoopLogger{},
+		Node:       &fakeNode{name: "kind-worker"},
+		CPNode:     &fakeNode{name: "kind-control-plane"},
+		Runner:     runner,
+		Runtime:    RuntimeSpecForNode(nil),
+		CSRTimeout: time.Second,
+	}
+
+	engine := NewEngine(DefaultActions()...)
+	if err := engine.Run(ctx); err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+
+	if ctx.Bootconf != "fake-bootstrap-conf-contents" {
+		t.Errorf("Bootconf = %q, want the fetched bootstrap.conf contents", ctx.Bootconf)
+	}
+	if len(runner.Calls) == 0 {
+		t.Error("expected the default actions to run commands against the fake runner")
+	}
+}
+
+// stubAction is a minimal Action used to test Engine's rollback behavior in
+// isolation from the concrete krustlet join actions.
+type stubAction struct {
+	name   string
+	failDo bool
+	done   *[]string
+	undone *[]string
+}
+
+func (a *stubAction) Name() string                  { return a.name }
+func (a *stubAction) Precondition(ctx *Context) error { return nil }
+
+func (a *stubAction) Do(ctx *Context) error {
+	if a.failDo {
+		return errors.New(a.name + " failed")
+	}
+	*a.done = append(*a.done, a.name)
+	return nil
+}
+
+func (a *stubAction) Undo(ctx *Context) error {
+	*a.undone = append(*a.undone, a.name)
+	return nil
+}
+
+// TestEngineRunRollsBackOnFailure verifies that when an action fails,
+// Engine.Run undoes every already-completed action in reverse order and
+// never runs the actions after the failure.
+func TestEngineRunRollsBackOnFailure(t *testing.T) {
+	var done, undone []string
+
+	engine := NewEngine(
+		&stubAction{name: "first", done: &done, undone: &undone},
+		&stubAction{name: "second", done: &done, undone: &undone},
+		&stubAction{name: "third", failDo: true, done: &done, undone: &undone},
+		&stubAction{name: "fourth", done: &done, undone: &undone},
+	)
+
+	err := engine.Run(&Context{Logger: log.NoopLogger{}})
+	if err == nil {
+		t.Fatal("Run() = nil, want an error from the failing action")
+	}
+
+	wantDone := []string{"first", "second"}
+	if !equalSlices(done, wantDone) {
+		t.Errorf("done actions = %v, want %v", done, wantDone)
+	}
+
+	wantUndone := []string{"second", "first"}
+	if !equalSlices(undone, wantUndone) {
+		t.Errorf("undone actions = %v, want %v (reverse completion order)", undone, wantUndone)
+	}
+}
+
+func equalSlices(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lifecycle implements the sequence of steps a krustlet worker
+// node goes through to join a kind cluster (PostProvision, MemberJoin,
+// AccountProvision-style steps in kind's own vocabulary) as a set of
+// named, idempotent Actions dispatched by an Engine. Third parties can
+// extend the join sequence with their own Actions (e.g. to load local OCI
+// WASM modules, apply taints, or register with a private registry)
+// without editing this package.
+package lifecycle
+
+import (
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/exec"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+// Context carries the state an Action needs to Do or Undo its work, and
+// the results earlier Actions in the same run produced for later ones.
+type Context struct {
+	Logger     log.Logger
+	Node       nodes.Node
+	CPNode     nodes.Node
+	Runner     exec.CommandRunner
+	Runtime    RuntimeSpec
+	CSRTimeout time.Duration
+
+	// RegistryEndpoint, when set, is the host:port of the per-cluster OCI
+	// registry `kind load wasm-module` pushes into. TrustRegistry
+	// provisions the node to trust it and plumbs it into the runtime's
+	// environment so krustlet can pull modules from it without the
+	// internet.
+	RegistryEndpoint string
+
+	// Bootconf is populated by FetchBootstrapConf and consumed by
+	// WriteKubeconfig.
+	Bootconf string
+}
+
+// Action is a single, named, idempotent step in the krustlet join
+// lifecycle.
+type Action interface {
+	// Name identifies the action for logging and error messages.
+	Name() string
+	// Precondition reports whether ctx has everything this action needs;
+	// the Engine calls it immediately before Do.
+	Precondition(ctx *Context) error
+	// Do performs the action. It must be safe to call more than once.
+	Do(ctx *Context) error
+	// Undo reverses Do, best-effort. The Engine calls it, in reverse
+	// registration order, on every already-completed action when a later
+	// one fails.
+	Undo(ctx *Context) error
+}
+
+// Engine runs a fixed sequence of Actions against a Context, rolling back
+// completed actions if one fails partway through.
+type Engine struct {
+	actions []Action
+}
+
+// NewEngine returns an Engine that runs actions in order.
+func NewEngine(actions ...Action) *Engine {
+	return &Engine{actions: actions}
+}
+
+// DefaultActions returns the built-in krustlet join sequence: fetch the
+// bootstrap conf, write it as the node's kubeconfig, install the pinned
+// runtime, trust the wasm module registry (if one is set), enable and
+// start its service, approve the resulting CSR, and label the node.
+func DefaultActions() []Action {
+	return []Action{
+		&FetchBootstrapConf{},
+		&WriteKubeconfig{},
+		&InstallRuntime{},
+		&TrustRegistry{},
+		&EnableService{},
+		&StartService{},
+		&ApproveCSR{},
+		&LabelNode{},
+	}
+}
+
+// Run executes every action in order. If an action's precondition or Do
+// fails, Run undoes every already-completed action, in reverse order,
+// before returning the original error.
+func (e *Engine) Run(ctx *Context) error {
+	done := make([]Action, 0, len(e.actions))
+	for _, a := range e.actions {
+		if err := a.Precondition(ctx); err != nil {
+			e.rollback(ctx, done)
+			return errors.Wrap(err, a.Name()+": precondition not met")
+		}
+		if err := a.Do(ctx); err != nil {
+			e.rollback(ctx, done)
+			return errors.Wrap(err, a.Name())
+		}
+		done = append(done, a)
+	}
+	return nil
+}
+
+func (e *Engine) rollback(ctx *Context, done []Action) {
+	for i := len(done) - 1; i >= 0; i-- {
+		if err := done[i].Undo(ctx); err != nil {
+			ctx.Logger.V(0).Info(fmt.Sprintf("failed to undo %s: %v", done[i].Name(), err))
+		}
+	}
+}
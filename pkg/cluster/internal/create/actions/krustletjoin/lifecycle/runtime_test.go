@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"testing"
+
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+)
+
+func TestRuntimeSpecForNode(t *testing.T) {
+	cases := []struct {
+		name        string
+		node        *v1alpha4.Node
+		wantRuntime KrustletRuntime
+		wantVersion string
+	}{
+		{
+			name:        "nil node defaults to wasi",
+			node:        nil,
+			wantRuntime: RuntimeWasi,
+			wantVersion: defaultPins[RuntimeWasi].Version,
+		},
+		{
+			name:        "unset runtime defaults to wasi",
+			node:        &v1alpha4.Node{},
+			wantRuntime: RuntimeWasi,
+			wantVersion: defaultPins[RuntimeWasi].Version,
+		},
+		{
+			name: "explicit runtime and pinned version are honored",
+			node: &v1alpha4.Node{
+				KrustletRuntime: &v1alpha4.KrustletRuntime{
+					Runtime: "wasmcloud",
+					Version: "v9.9.9",
+				},
+			},
+			wantRuntime: RuntimeWasmCloud,
+			wantVersion: "v9.9.9",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := RuntimeSpecForNode(tc.node)
+			if spec.Runtime != tc.wantRuntime {
+				t.Errorf("Runtime = %q, want %q", spec.Runtime, tc.wantRuntime)
+			}
+			if spec.Version != tc.wantVersion {
+				t.Errorf("Version = %q, want %q", spec.Version, tc.wantVersion)
+			}
+		})
+	}
+}
+
+func TestRuntimeSpecNodeLabels(t *testing.T) {
+	spec := RuntimeSpec{Runtime: RuntimeWascc}
+	labels := spec.NodeLabels()
+	if labels["kubernetes.io/arch"] != "wasm32" {
+		t.Errorf("expected wasm32 arch label, got %q", labels["kubernetes.io/arch"])
+	}
+	if labels["krustlet.dev/runtime"] != "wascc" {
+		t.Errorf("expected wascc runtime label, got %q", labels["krustlet.dev/runtime"])
+	}
+}
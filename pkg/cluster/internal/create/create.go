@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package create implements the `create cluster` action pipeline
+package create
+
+import (
+	"time"
+
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	"sigs.k8s.io/kind/pkg/cluster/internal/create/actions"
+	"sigs.k8s.io/kind/pkg/cluster/internal/create/actions/krustletjoin"
+	"sigs.k8s.io/kind/pkg/cluster/internal/providers"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+// Options holds the user-configurable parts of a cluster creation run that
+// aren't part of the kind config itself.
+type Options struct {
+	// CSRTimeout bounds how long krustletjoin waits for each worker's
+	// kubelet CSR to appear before giving up. Zero keeps the action's own
+	// default.
+	CSRTimeout time.Duration
+}
+
+// Cluster runs the cluster creation action pipeline for cfg against
+// provider, configured by opts.
+func Cluster(logger log.Logger, provider providers.Provider, cfg *v1alpha4.Cluster, opts Options) error {
+	actionsToRun := []actions.Action{
+		krustletjoin.NewAction(krustletJoinOptions(opts)...),
+	}
+
+	ctx := actions.NewActionContext(logger, provider, cfg)
+	for _, a := range actionsToRun {
+		if err := a.Execute(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// krustletJoinOptions translates Options into the krustletjoin.Options
+// that apply to this run.
+func krustletJoinOptions(opts Options) []krustletjoin.Option {
+	var joinOpts []krustletjoin.Option
+	if opts.CSRTimeout > 0 {
+		joinOpts = append(joinOpts, krustletjoin.WithCSRTimeout(opts.CSRTimeout))
+	}
+	return joinOpts
+}
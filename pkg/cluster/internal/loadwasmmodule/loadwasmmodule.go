@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package loadwasmmodule implements `kind load wasm-module`: pushing a
+// local OCI-format wasm artifact into a per-cluster in-cluster registry
+// and configuring krustlet nodes to trust it, mirroring how loaddocker
+// image implements `kind load docker-image`.
+package loadwasmmodule
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/cluster/internal/create/actions/krustletjoin/lifecycle"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/exec"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+const (
+	registryContainerPrefix = "kind-registry-"
+	registryImage           = "registry:2"
+	registryPort            = 5000
+	dockerNetwork           = "kind"
+)
+
+// Endpoints holds the two addresses a per-cluster wasm module registry is
+// reachable at: ClusterEndpoint, which only resolves from containers on
+// the cluster's docker network (what krustlet nodes are told to trust),
+// and HostEndpoint, the loopback address `oras push` (run from the host)
+// actually pushes to.
+type Endpoints struct {
+	ClusterEndpoint string
+	HostEndpoint    string
+}
+
+// EndpointForCluster returns the Endpoints of cluster's in-cluster wasm
+// module registry, creating it - attached to the cluster's docker network,
+// with its port published to a host loopback port - on first use.
+func EndpointForCluster(logger log.Logger, runner exec.CommandRunner, clusterName string) (Endpoints, error) {
+	name := registryContainerPrefix + clusterName
+	endpoints := Endpoints{ClusterEndpoint: fmt.Sprintf("%s:%d", name, registryPort)}
+
+	running, err := runner.RunCmd(exec.Command("docker", "inspect", "-f", "{{.State.Running}}", name))
+	if err != nil || len(running) == 0 || strings.TrimSpace(running[0]) != "true" {
+		logger.V(0).Info("Creating in-cluster wasm module registry 🦀")
+		if _, err := runner.RunCmd(exec.Command(
+			"docker", "run", "-d", "--restart=always",
+			"--name", name,
+			"--network", dockerNetwork,
+			"-p", "127.0.0.1::"+fmt.Sprint(registryPort),
+			registryImage,
+		)); err != nil {
+			return Endpoints{}, errors.Wrap(err, "failed to start wasm module registry")
+		}
+	}
+
+	hostPort, err := runner.RunCmd(exec.Command(
+		"docker", "inspect", "-f",
+		fmt.Sprintf(`{{(index (index .NetworkSettings.Ports "%d/tcp") 0).HostPort}}`, registryPort),
+		name,
+	))
+	if err != nil {
+		return Endpoints{}, errors.Wrap(err, "failed to read wasm module registry's published host port")
+	}
+	if len(hostPort) == 0 || strings.TrimSpace(hostPort[0]) == "" {
+		return Endpoints{}, errors.New("failed to read wasm module registry's published host port")
+	}
+
+	endpoints.HostEndpoint = fmt.Sprintf("127.0.0.1:%s", strings.TrimSpace(hostPort[0]))
+	return endpoints, nil
+}
+
+// Push pushes the OCI-format wasm artifact at modulePath to hostEndpoint
+// (the registry's host-reachable address, see Endpoints.HostEndpoint),
+// tagged as tag (e.g. "mymod:latest"). It shells out to the oras CLI
+// (https://oras.land), which must be installed on the host separately;
+// kind does not vendor it.
+func Push(runner exec.CommandRunner, modulePath, hostEndpoint, tag string) error {
+	ref := fmt.Sprintf("%s/%s", hostEndpoint, tag)
+	file := modulePath + ":application/vnd.wasm.content.layer.v1+wasm"
+	_, err := runner.RunCmd(exec.Command("oras", "push", ref, file))
+	return errors.Wrap(err, "failed to push wasm module (is the oras CLI installed? see https://oras.land)")
+}
+
+// DetectRuntime returns the RuntimeSpec of the krustlet runtime already
+// installed on node, so TrustRegistry can plumb the registry endpoint
+// into the same systemd unit krustletjoin created.
+func DetectRuntime(runner exec.CommandRunner, node nodes.Node) (lifecycle.RuntimeSpec, error) {
+	lines, err := runner.RunCmd(node.Command(
+		"bash", "-c", "ls /etc/systemd/system/krustlet-*.service 2>/dev/null | head -n1",
+	))
+	if err != nil || len(lines) == 0 || strings.TrimSpace(lines[0]) == "" {
+		return lifecycle.RuntimeSpec{}, errors.New("node has no krustlet runtime installed")
+	}
+
+	unit := strings.TrimSuffix(filepath.Base(strings.TrimSpace(lines[0])), ".service")
+	runtime := strings.TrimPrefix(unit, "krustlet-")
+	return lifecycle.RuntimeSpec{Runtime: lifecycle.KrustletRuntime(runtime)}, nil
+}
+
+// Load pushes modulePath into cluster's in-cluster registry and
+// configures every node in targets to trust it, restarting krustlet on
+// each so it picks up the new registry endpoint.
+func Load(logger log.Logger, runner exec.CommandRunner, targets []nodes.Node, clusterName, modulePath, tag string) error {
+	endpoints, err := EndpointForCluster(logger, runner, clusterName)
+	if err != nil {
+		return err
+	}
+
+	if err := Push(runner, modulePath, endpoints.HostEndpoint, tag); err != nil {
+		return err
+	}
+
+	for _, node := range targets {
+		spec, err := DetectRuntime(runner, node)
+		if err != nil {
+			return errors.Wrap(err, "node "+node.String()+" is not a krustlet node")
+		}
+
+		ctx := &lifecycle.Context{
+			Logger:           logger,
+			Node:             node,
+			Runner:           runner,
+			Runtime:          spec,
+			RegistryEndpoint: endpoints.ClusterEndpoint,
+		}
+		if err := (&lifecycle.TrustRegistry{}).Do(ctx); err != nil {
+			return err
+		}
+		if _, err := runner.RunCmd(node.Command("systemctl", "restart", spec.UnitName())); err != nil {
+			return errors.Wrap(err, "failed to restart krustlet after trusting registry")
+		}
+	}
+
+	logger.V(0).Info(fmt.Sprintf("Pushed %s to %s/%s, trusted by %d node(s)", modulePath, endpoints.ClusterEndpoint, tag, len(targets)))
+	return nil
+}
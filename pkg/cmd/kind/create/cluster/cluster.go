@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster implements the `create cluster` command
+package cluster
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	"sigs.k8s.io/kind/pkg/cluster/internal/create"
+	"sigs.k8s.io/kind/pkg/cluster/internal/providers"
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+type flagpole struct {
+	Name       string
+	CSRTimeout time.Duration
+}
+
+// NewCommand returns a new cobra.Command for creating a kind cluster.
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	flags := &flagpole{}
+	c := &cobra.Command{
+		Use:   "cluster",
+		Short: "Creates a local Kubernetes cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(logger, flags)
+		},
+	}
+	c.Flags().StringVar(&flags.Name, "name", "kind", "cluster context name")
+	c.Flags().DurationVar(
+		&flags.CSRTimeout, "csr-timeout", 30*time.Second,
+		"how long to wait for a krustlet worker's kubelet CSR to appear before giving up; "+
+			"CI environments with slow cold starts may need to raise this",
+	)
+	return c
+}
+
+func runE(logger log.Logger, flags *flagpole) error {
+	if flags.CSRTimeout <= 0 {
+		return errors.Errorf("--csr-timeout must be positive, got %s", flags.CSRTimeout)
+	}
+
+	provider := providers.NewDefaultProvider(logger)
+	cfg := &v1alpha4.Cluster{Name: flags.Name}
+
+	return create.Cluster(logger, provider, cfg, create.Options{
+		CSRTimeout: flags.CSRTimeout,
+	})
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package load implements the `load` command
+package load
+
+import (
+	"github.com/spf13/cobra"
+
+	wasmmodule "sigs.k8s.io/kind/pkg/cmd/kind/load/wasm-module"
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+// NewCommand returns a new cobra.Command for loading images/artifacts into
+// a cluster's nodes.
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	c := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "load",
+		Short: "Loads images/artifacts into a cluster's nodes",
+	}
+	c.AddCommand(wasmmodule.NewCommand(logger, streams))
+	return c
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package wasmmodule implements the `load wasm-module` command
+package wasmmodule
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/constants"
+	"sigs.k8s.io/kind/pkg/cluster/internal/loadwasmmodule"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+	"sigs.k8s.io/kind/pkg/cmd"
+	"sigs.k8s.io/kind/pkg/errors"
+	"sigs.k8s.io/kind/pkg/exec"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+type flagpole struct {
+	Name  string
+	Nodes []string
+}
+
+// NewCommand returns a new cobra.Command for loading a local OCI-format
+// wasm module into a krustlet node, mirroring `kind load docker-image`.
+func NewCommand(logger log.Logger, streams cmd.IOStreams) *cobra.Command {
+	flags := &flagpole{}
+	c := &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Use:   "wasm-module [module]",
+		Short: "Loads a local OCI-format wasm module into a krustlet node",
+		Long: "Loads a local OCI-format wasm module into a per-cluster in-cluster OCI registry (created on first use), " +
+			"and configures the target krustlet node(s) to trust it, so it can be pulled with " +
+			"`kubectl run --image=<registry>/<module>` without standing up an external registry.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(logger, flags, args)
+		},
+	}
+	c.Flags().StringVar(&flags.Name, "name", cluster.DefaultName, "the cluster context name")
+	c.Flags().StringSliceVar(&flags.Nodes, "nodes", nil, "comma separated list of nodes to trust the registry on, defaults to all krustlet nodes")
+	return c
+}
+
+func runE(logger log.Logger, flags *flagpole, args []string) error {
+	modulePath := args[0]
+	if filepath.Ext(modulePath) != ".wasm" {
+		logger.Warn("module does not have a .wasm extension")
+	}
+
+	provider := cluster.NewProvider(cluster.ProviderWithLogger(logger))
+
+	allNodes, err := provider.ListNodes(flags.Name)
+	if err != nil {
+		return err
+	}
+	if len(allNodes) == 0 {
+		return errors.Errorf("no nodes found for cluster %q", flags.Name)
+	}
+
+	var targets []nodes.Node
+	if len(flags.Nodes) > 0 {
+		targets = filterNodesByName(allNodes, flags.Nodes)
+		if len(targets) == 0 {
+			return errors.Errorf("no matching nodes found for %v", flags.Nodes)
+		}
+	} else {
+		targets, err = nodeutils.SelectNodesByRole(allNodes, constants.KrustletNodeRoleValue)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return errors.Errorf("no krustlet nodes found for cluster %q, use --nodes to target specific nodes", flags.Name)
+		}
+	}
+
+	tag := strings.TrimSuffix(filepath.Base(modulePath), filepath.Ext(modulePath)) + ":latest"
+
+	return loadwasmmodule.Load(logger, exec.DefaultCommandRunner{}, targets, flags.Name, modulePath, tag)
+}
+
+func filterNodesByName(allNodes []nodes.Node, names []string) []nodes.Node {
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+	filtered := []nodes.Node{}
+	for _, node := range allNodes {
+		if wanted[node.String()] {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides fakes for pkg/exec, so packages that shell out to
+// nodes can be unit-tested without a real container.
+package fake
+
+import (
+	"io"
+
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+// Call records a single invocation made against a CommandRunner.
+type Call struct {
+	Cmd   exec.Cmd
+	Stdin string
+}
+
+// CommandRunner is a fake exec.CommandRunner that records the commands it
+// was asked to run and returns canned output, keyed by whatever the
+// caller's Key function returns for each command.
+type CommandRunner struct {
+	// Outputs maps a command key (set by the caller, see Key) to the lines
+	// it should return. A missing key returns no output and a nil error.
+	Outputs map[string][]string
+	// Errors maps a command key to the error RunCmd/RunWithStdin should
+	// return instead of succeeding.
+	Errors map[string]error
+	// Calls records every invocation, in order.
+	Calls []Call
+	// Key computes the lookup key for a command. When it is nil, every
+	// command maps to the empty key "".
+	Key func(cmd exec.Cmd) string
+}
+
+var _ exec.CommandRunner = &CommandRunner{}
+
+func (r *CommandRunner) key(cmd exec.Cmd) string {
+	if r.Key != nil {
+		return r.Key(cmd)
+	}
+	return ""
+}
+
+// RunCmd implements exec.CommandRunner
+func (r *CommandRunner) RunCmd(cmd exec.Cmd) ([]string, error) {
+	return r.RunWithStdin(cmd, nil)
+}
+
+// RunWithStdin implements exec.CommandRunner
+func (r *CommandRunner) RunWithStdin(cmd exec.Cmd, stdin io.Reader) ([]string, error) {
+	var stdinStr string
+	if stdin != nil {
+		b, _ := io.ReadAll(stdin)
+		stdinStr = string(b)
+	}
+	r.Calls = append(r.Calls, Call{Cmd: cmd, Stdin: stdinStr})
+
+	k := r.key(cmd)
+	if err, ok := r.Errors[k]; ok {
+		return r.Outputs[k], err
+	}
+	return r.Outputs[k], nil
+}
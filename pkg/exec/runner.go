@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import "io"
+
+// CommandRunner abstracts running a Cmd to completion, optionally feeding
+// it stdin. It exists so callers that need to pipe data into a command
+// (instead of only passing args) can be swapped for a fake in tests,
+// without depending on a concrete node or container implementation.
+type CommandRunner interface {
+	// RunCmd runs cmd to completion and returns its combined stdout/stderr,
+	// line by line.
+	RunCmd(cmd Cmd) ([]string, error)
+	// RunWithStdin runs cmd to completion with stdin wired to its standard
+	// input, and returns its combined stdout/stderr, line by line.
+	RunWithStdin(cmd Cmd, stdin io.Reader) ([]string, error)
+}
+
+// DefaultCommandRunner is the CommandRunner used outside of tests: it runs
+// cmd exactly as given.
+type DefaultCommandRunner struct{}
+
+var _ CommandRunner = DefaultCommandRunner{}
+
+// RunCmd implements CommandRunner
+func (DefaultCommandRunner) RunCmd(cmd Cmd) ([]string, error) {
+	return CombinedOutputLines(cmd)
+}
+
+// RunWithStdin implements CommandRunner
+func (DefaultCommandRunner) RunWithStdin(cmd Cmd, stdin io.Reader) ([]string, error) {
+	cmd.SetStdin(stdin)
+	return CombinedOutputLines(cmd)
+}